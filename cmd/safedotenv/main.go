@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/akamensky/argparse"
+
+	"github.com/Napolitain/safedotenv/internal/configfile"
+	"github.com/Napolitain/safedotenv/internal/contentenc"
+	"github.com/Napolitain/safedotenv/internal/cryptocore"
+)
+
+// SuffixEncrypted is appended to a file's name to name its encrypted
+// counterpart, and stripped back off to recover the original name.
+const SuffixEncrypted = "-encrypted"
+
+func encryptFile(inputPath string, passphrase []byte, keyfileDigests [][]byte, opts contentenc.Options) error {
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(inputPath + SuffixEncrypted)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	writer := bufio.NewWriter(out)
+	if err := contentenc.Encrypt(bufio.NewReader(in), writer, uint64(info.Size()), passphrase, keyfileDigests, opts, inputPath); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+func decryptFile(inputPath string, passphrase []byte, keyfileDigests [][]byte, opts contentenc.DecryptOptions) error {
+	in, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	outputPath := inputPath[:len(inputPath)-len(SuffixEncrypted)]
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	writer := bufio.NewWriter(out)
+	if err := contentenc.Decrypt(bufio.NewReader(in), writer, passphrase, keyfileDigests, opts, inputPath); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// loadKeyfileDigests reads and BLAKE2b-512-hashes each keyfile in paths, in
+// order, so the caller never has to keep raw keyfile contents around longer
+// than it takes to hash them.
+func loadKeyfileDigests(paths []string) ([][]byte, error) {
+	digests := make([][]byte, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		digests = append(digests, cryptocore.HashKeyfile(data))
+	}
+	return digests, nil
+}
+
+func main() {
+	// Load this directory's saved defaults, if any, before the flags are
+	// declared below, so a repeat run doesn't need its non-default choices
+	// (KDF, --paranoid, --reed-solomon) re-specified on the command line.
+	// The config file is looked up relative to "." since --directory itself
+	// hasn't been parsed yet; that matches --directory's own default.
+	cfg, err := configfile.Load(".")
+	if err != nil {
+		log.Fatal("Error reading config:", err)
+	}
+
+	// Argument parsing
+	parser := argparse.NewParser("safedotenv", "Securely store .env files using AES encryption on Github repositories for convenience.")
+	// --encrypt flag is optional boolean flag to encrypt the file instead of standardly decrypting
+	encrypt := parser.Flag("e", "encrypt", &argparse.Options{Required: false, Default: false, Help: "Encrypt .env to .env-encrypted instead of decrypting .env-encrypted to .env"})
+	folder := parser.String("d", "directory", &argparse.Options{Required: false, Default: ".", Help: "Directory to scan for files to encrypt/decrypt"})
+	kdfFlag := parser.Selector("k", "kdf", []string{"pbkdf2", "argon2id"}, &argparse.Options{Required: false, Default: cfg.KDF, Help: "Key derivation function to use when encrypting"})
+	// argparse's Flag type ignores Options.Default entirely when it's true
+	// (a *bool always starts false; see the library's own
+	// TestFlagDefaultValueShouldIgnoreTrue), so a saved --paranoid/
+	// --reed-solomon can't be wired through Default like kdfFlag above. Each
+	// is instead OR'd with the saved config value below, after parsing.
+	paranoid := parser.Flag("p", "paranoid", &argparse.Options{Required: false, Default: false, Help: "Layer Serpent-256 over AES-256 in a cascade for defense-in-depth"})
+	reedSolomon := parser.Flag("r", "reed-solomon", &argparse.Options{Required: false, Default: false, Help: "Add Reed-Solomon parity so minor bit-rot doesn't destroy the whole file"})
+	argon2Time := parser.Int("", "argon2-time", &argparse.Options{Required: false, Default: int(cfg.Argon2Time), Help: "Argon2id time (number of passes) to use when --kdf=argon2id"})
+	argon2MemoryKB := parser.Int("", "argon2-memory", &argparse.Options{Required: false, Default: int(cfg.Argon2MemoryKB), Help: "Argon2id memory in KiB to use when --kdf=argon2id"})
+	argon2Parallelism := parser.Int("", "argon2-parallelism", &argparse.Options{Required: false, Default: int(cfg.Argon2Parallelism), Help: "Argon2id parallelism to use when --kdf=argon2id"})
+	fix := parser.Flag("f", "fix", &argparse.Options{Required: false, Default: false, Help: "Report how many Reed-Solomon shards were repaired while decrypting"})
+	keepCorrupted := parser.Flag("c", "keep-corrupted", &argparse.Options{Required: false, Default: false, Help: "Write best-effort plaintext even when final authentication fails"})
+	keyfiles := parser.List("K", "keyfile", &argparse.Options{Required: false, Help: "Path to a keyfile to combine with (or substitute for) the passphrase; may be repeated"})
+	err = parser.Parse(os.Args)
+	if err != nil {
+		// In case of error print error and print usage
+		// This can also be done by passing -h or --help flags
+		fmt.Print(parser.Usage(err))
+	}
+
+	keyfileDigests, err := loadKeyfileDigests(*keyfiles)
+	if err != nil {
+		log.Fatal("Error reading keyfile:", err)
+	}
+
+	// Scan user input for passphrase; blank is fine when keyfiles cover
+	// authentication on their own.
+	passphrase, err := getPassphrase()
+	if err != nil {
+		log.Fatal("Error reading passphrase:", err)
+	}
+	if len(passphrase) == 0 && len(keyfileDigests) == 0 {
+		log.Fatal("Refusing to run with neither a passphrase nor a keyfile: the file(s) would be encrypted with a well-known, zero-secret key")
+	}
+
+	// Saved config values are additive, not overridden by the flags' zero
+	// values: once a directory has been set up with --paranoid or
+	// --reed-solomon there's no way to ask for "off" on the command line,
+	// only to add the other on top.
+	useParanoid := *paranoid || cfg.Paranoid
+	useReedSolomon := *reedSolomon || cfg.ReedSolomon
+
+	encOpts := contentenc.DefaultOptions()
+	if *kdfFlag == "argon2id" {
+		encOpts.KDF = cryptocore.KDFArgon2id
+	}
+	encOpts.Argon2Time = uint32(*argon2Time)
+	encOpts.Argon2MemoryKB = uint32(*argon2MemoryKB)
+	encOpts.Argon2Parallelism = uint8(*argon2Parallelism)
+	if useParanoid {
+		encOpts.CipherMode = cryptocore.CipherModeParanoidCascade
+	}
+	encOpts.ReedSolomon = useReedSolomon
+
+	decOpts := contentenc.DecryptOptions{Fix: *fix, KeepCorrupted: *keepCorrupted}
+
+	// Declare file paths as an empty vector of filepaths to concatenate when we scan later on
+	filePaths := getDotenvPaths(folder, encrypt)
+
+	// Iterate over the file paths and encrypt/decrypt them
+	processDotenvFiles(filePaths, encrypt, passphrase, keyfileDigests, encOpts, decOpts)
+
+	// Persist the options this run encrypted with, so a later run in the
+	// same directory picks them up as its defaults without repeating flags.
+	if *encrypt {
+		cfg.KDF = *kdfFlag
+		cfg.PBKDF2Iterations = cryptocore.PBKDF2Iterations
+		cfg.Argon2Time = encOpts.Argon2Time
+		cfg.Argon2MemoryKB = encOpts.Argon2MemoryKB
+		cfg.Argon2Parallelism = encOpts.Argon2Parallelism
+		cfg.Paranoid = useParanoid
+		cfg.ReedSolomon = useReedSolomon
+		cfg.FormatVersion = contentenc.FormatVersion
+		if err := configfile.Save(*folder, cfg); err != nil {
+			log.Println("Error saving config:", err)
+		}
+	}
+
+	log.Println("Done.")
+}
+
+// maxConcurrentFiles bounds how many files are encrypted/decrypted at once,
+// so a directory with thousands of .env files doesn't exhaust file
+// descriptors by opening all of them simultaneously.
+const maxConcurrentFiles = 16
+
+func processDotenvFiles(filePaths []string, encrypt *bool, passphrase []byte, keyfileDigests [][]byte, encOpts contentenc.Options, decOpts contentenc.DecryptOptions) {
+	var wg sync.WaitGroup
+	channel := make(chan error, len(filePaths)) // Buffered channel to avoid blocking
+	sem := make(chan struct{}, maxConcurrentFiles)
+
+	for _, filePath := range filePaths {
+		wg.Add(1)
+		sem <- struct{}{}
+		if *encrypt {
+			go func(filePath string) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				err := encryptFile(filePath, passphrase, keyfileDigests, encOpts)
+				channel <- err
+			}(filePath)
+		} else {
+			go func(filePath string) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				err := decryptFile(filePath, passphrase, keyfileDigests, decOpts)
+				channel <- err
+			}(filePath)
+		}
+	}
+
+	// Wait for all goroutines to finish
+	wg.Wait()
+	close(channel)
+
+	// Collect and log errors
+	for err := range channel {
+		if err != nil {
+			log.Println("Error:", err)
+		}
+	}
+}
+
+func getDotenvPaths(folder *string, encrypt *bool) []string {
+	var filePaths []string
+	// Use a stack to process directories iteratively
+	stack := []string{*folder}
+
+	for len(stack) > 0 {
+		// Pop a directory from the stack
+		currentDir := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		// Read the contents of the current directory
+		files, err := os.ReadDir(currentDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Iterate over the files and append their paths to the filePaths vector
+		for _, file := range files {
+			if file.IsDir() {
+				// Add subdirectory to the stack
+				stack = append(stack, currentDir+"/"+file.Name())
+			} else if file.Name() == ".env" && *encrypt == true {
+				filePaths = append(filePaths, currentDir+"/"+file.Name())
+			} else if file.Name() == ".env-encrypted" && *encrypt == false {
+				filePaths = append(filePaths, currentDir+"/"+file.Name())
+			}
+		}
+	}
+	log.Println(filePaths)
+	return filePaths
+}
+
+func getPassphrase() ([]byte, error) {
+	// Get user input for the key. A blank line is accepted (not an error)
+	// so --keyfile-only runs don't force the user to type a passphrase.
+	log.Println("Enter passphrase (leave blank if using only keyfiles): ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return []byte(strings.TrimRight(line, "\r\n")), nil
+}
@@ -0,0 +1,75 @@
+// Package configfile reads and writes the per-directory .safedotenv.json
+// config file, which records the encryption defaults (KDF choice,
+// iteration/cost parameters, and feature flags) a directory of .env files
+// was set up with, so repeat runs don't need the flags re-specified.
+package configfile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/Napolitain/safedotenv/internal/contentenc"
+	"github.com/Napolitain/safedotenv/internal/cryptocore"
+)
+
+// FileName is the name of the config file Load/Save look for in a
+// directory.
+const FileName = ".safedotenv.json"
+
+// Config is the persisted form of the encryption defaults for a directory.
+type Config struct {
+	KDF string `json:"kdf"`
+
+	PBKDF2Iterations uint32 `json:"pbkdf2_iterations"`
+
+	Argon2Time        uint32 `json:"argon2_time"`
+	Argon2MemoryKB    uint32 `json:"argon2_memory_kb"`
+	Argon2Parallelism uint8  `json:"argon2_parallelism"`
+
+	Paranoid    bool `json:"paranoid"`
+	ReedSolomon bool `json:"reed_solomon"`
+
+	FormatVersion uint16 `json:"format_version"`
+}
+
+// Default returns the config matching contentenc.DefaultOptions, reading its
+// constants from cryptocore/contentenc rather than duplicating them so the
+// two can't drift out of sync as the file format evolves.
+func Default() *Config {
+	return &Config{
+		KDF:               "pbkdf2",
+		PBKDF2Iterations:  cryptocore.PBKDF2Iterations,
+		Argon2Time:        cryptocore.Argon2TimeDefault,
+		Argon2MemoryKB:    cryptocore.Argon2MemoryKBDefault,
+		Argon2Parallelism: cryptocore.Argon2ParallelismDef,
+		FormatVersion:     contentenc.FormatVersion,
+	}
+}
+
+// Load reads dir's config file. If it does not exist, Load returns
+// Default() rather than an error, since an absent config file just means a
+// directory hasn't been set up with non-default options yet.
+func Load(dir string) (*Config, error) {
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if os.IsNotExist(err) {
+		return Default(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Save writes cfg as dir's config file, creating or overwriting it.
+func Save(dir string, cfg *Config) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, FileName), data, 0644)
+}
@@ -0,0 +1,94 @@
+package contentenc
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"io"
+
+	"github.com/schollz/progressbar/v3"
+
+	"github.com/Napolitain/safedotenv/internal/cryptocore"
+)
+
+// numChunksFor returns how many chunkPlaintextSize chunks a plaintext of the
+// given length splits into. An empty file still gets one (empty) chunk, so
+// every encrypted file has at least one AAD-protected "last chunk" marker.
+func numChunksFor(plaintextLen int64) int64 {
+	if plaintextLen == 0 {
+		return 1
+	}
+	return (plaintextLen + chunkPlaintextSize - 1) / chunkPlaintextSize
+}
+
+// chunkNonce builds the per-chunk GCM nonce from the file's random nonce
+// (only the first 8 bytes of which are used) and a big-endian chunk counter.
+func chunkNonce(fileNonce []byte, counter uint32) []byte {
+	nonce := make([]byte, cryptocore.NonceSize)
+	copy(nonce, fileNonce[:8])
+	binary.BigEndian.PutUint32(nonce[8:12], counter)
+	return nonce
+}
+
+// chunkAAD binds a chunk's ciphertext to the file header, its position, and
+// whether it's the last chunk, so chunks can't be reordered, spliced between
+// files, or silently dropped off the end.
+func chunkAAD(headerBytes []byte, counter uint32, isLast bool) []byte {
+	aad := make([]byte, len(headerBytes)+5)
+	copy(aad, headerBytes)
+	binary.BigEndian.PutUint32(aad[len(headerBytes):], counter)
+	if isLast {
+		aad[len(aad)-1] = 1
+	}
+	return aad
+}
+
+// encryptChunked streams plaintext from in and writes independently
+// GCM-sealed chunks to out, advancing bar as each chunk is read. It never
+// holds more than one chunk of plaintext in memory.
+func encryptChunked(in io.Reader, out io.Writer, gcm cipher.AEAD, fileNonce, headerBytes []byte, plaintextLen uint64, bar *progressbar.ProgressBar) error {
+	numChunks := numChunksFor(int64(plaintextLen))
+	buf := make([]byte, chunkPlaintextSize)
+	for i := int64(0); i < numChunks; i++ {
+		n, err := io.ReadFull(in, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		chunk := buf[:n]
+		isLast := i == numChunks-1
+		ciphertext := gcm.Seal(nil, chunkNonce(fileNonce, uint32(i)), chunk, chunkAAD(headerBytes, uint32(i), isLast))
+		if _, err := out.Write(ciphertext); err != nil {
+			return err
+		}
+		_ = bar.Add(n)
+	}
+	return nil
+}
+
+// decryptChunked reverses encryptChunked, verifying and writing out one
+// chunk's plaintext at a time instead of buffering the whole payload.
+func decryptChunked(in io.Reader, out io.Writer, gcm cipher.AEAD, fileNonce, headerBytes []byte, plaintextLen uint64, bar *progressbar.ProgressBar) error {
+	numChunks := numChunksFor(int64(plaintextLen))
+	ciphertextChunkSize := chunkPlaintextSize + cryptocore.GCMOverhead
+	buf := make([]byte, ciphertextChunkSize)
+	for i := int64(0); i < numChunks; i++ {
+		remainingPlaintext := plaintextLen - uint64(i)*chunkPlaintextSize
+		wantLen := int(remainingPlaintext) + cryptocore.GCMOverhead
+		if wantLen > ciphertextChunkSize {
+			wantLen = ciphertextChunkSize
+		}
+		n, err := io.ReadFull(in, buf[:wantLen])
+		if err != nil {
+			return ErrTruncatedFile
+		}
+		isLast := i == numChunks-1
+		plaintext, err := gcm.Open(nil, chunkNonce(fileNonce, uint32(i)), buf[:n], chunkAAD(headerBytes, uint32(i), isLast))
+		if err != nil {
+			return cryptocore.ErrAuthenticationFailed
+		}
+		if _, err := out.Write(plaintext); err != nil {
+			return err
+		}
+		_ = bar.Add(len(plaintext))
+	}
+	return nil
+}
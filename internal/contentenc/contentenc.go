@@ -0,0 +1,195 @@
+package contentenc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/schollz/progressbar/v3"
+
+	"github.com/Napolitain/safedotenv/internal/cryptocore"
+)
+
+// keyModeFor chooses the KeyMode recorded in the header, purely from
+// whether a passphrase and/or keyfiles were supplied.
+func keyModeFor(havePassphrase, haveKeyfiles bool) cryptocore.KeyMode {
+	switch {
+	case haveKeyfiles && havePassphrase:
+		return cryptocore.KeyModeCombined
+	case haveKeyfiles:
+		return cryptocore.KeyModeKeyfileOnly
+	default:
+		return cryptocore.KeyModePassphraseOnly
+	}
+}
+
+// Encrypt reads plaintextLen bytes from in, and writes a complete
+// .env-encrypted stream (header followed by payload) to out, following
+// opts. keyfileDigests are the BLAKE2b-512 digests of any keyfiles supplied
+// (see cryptocore.HashKeyfile); pass nil for passphrase-only encryption.
+// label is used only to annotate the progress bar (typically the source
+// file path).
+//
+// Reed-Solomon and the paranoid cascade need the whole sealed payload in
+// memory to align it into fixed-size blocks (or compute a single whole-buffer
+// MAC), so those paths buffer; the common case (plain AES-GCM, no parity)
+// streams straight through without ever holding the full file in memory.
+func Encrypt(in io.Reader, out io.Writer, plaintextLen uint64, passphrase []byte, keyfileDigests [][]byte, opts Options, label string) error {
+	salt := make([]byte, cryptocore.SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	nonce := make([]byte, cryptocore.NonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	opts.KeyMode = keyModeFor(len(passphrase) > 0, len(keyfileDigests) > 0)
+	opts.KeyfileCount = uint8(len(keyfileDigests))
+
+	headerBytes, h := BuildHeader(opts, salt, nonce, plaintextLen)
+	var keyfileKey []byte
+	if len(keyfileDigests) > 0 {
+		keyfileKey = cryptocore.CombineKeyfileDigests(keyfileDigests)
+	}
+	masterKey := cryptocore.DeriveMasterKeyWithKeyfile(passphrase, h.KDFParams(), keyfileKey, h.KeyMode)
+	bar := progressbar.DefaultBytes(int64(plaintextLen), "encrypting "+label)
+
+	if opts.ReedSolomon || opts.CipherMode == cryptocore.CipherModeParanoidCascade {
+		var payload []byte
+		switch opts.CipherMode {
+		case cryptocore.CipherModeParanoidCascade:
+			plaintext, err := io.ReadAll(in)
+			if err != nil {
+				return err
+			}
+			payload, err = cryptocore.CascadeEncrypt(masterKey, nonce, headerBytes, plaintext)
+			if err != nil {
+				return err
+			}
+		default:
+			var buf bytes.Buffer
+			gcm, err := cryptocore.NewGCM(masterKey)
+			if err != nil {
+				return err
+			}
+			if err := encryptChunked(in, &buf, gcm, nonce, headerBytes, plaintextLen, bar); err != nil {
+				return err
+			}
+			payload = buf.Bytes()
+		}
+
+		if opts.ReedSolomon {
+			var err error
+			payload, err = rsEncode(payload, int(h.RSDataBlockSize), int(h.RSParityBlockSize))
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, err := out.Write(headerBytes); err != nil {
+			return err
+		}
+		_, err := out.Write(payload)
+		return err
+	}
+
+	if _, err := out.Write(headerBytes); err != nil {
+		return err
+	}
+	gcm, err := cryptocore.NewGCM(masterKey)
+	if err != nil {
+		return err
+	}
+	return encryptChunked(in, out, gcm, nonce, headerBytes, plaintextLen, bar)
+}
+
+// Decrypt reads a complete .env-encrypted stream from in and writes the
+// recovered plaintext to out, following opts. keyfileDigests are the
+// BLAKE2b-512 digests of any keyfiles supplied; Decrypt fails fast with
+// ErrKeyfileMismatch if their count doesn't match what the file was
+// encrypted with. label is used only to annotate the progress bar and log
+// messages (typically the source file path).
+func Decrypt(in io.Reader, out io.Writer, passphrase []byte, keyfileDigests [][]byte, opts DecryptOptions, label string) error {
+	headerBytes := make([]byte, HeaderSize)
+	if _, err := io.ReadFull(in, headerBytes); err != nil {
+		return ErrTruncatedFile
+	}
+	h, err := ParseHeader(headerBytes)
+	if err != nil {
+		return err
+	}
+	if int(h.KeyfileCount) != len(keyfileDigests) {
+		return fmt.Errorf("%w: file was encrypted with %d keyfile(s), got %d", ErrKeyfileMismatch, h.KeyfileCount, len(keyfileDigests))
+	}
+
+	var keyfileKey []byte
+	if len(keyfileDigests) > 0 {
+		keyfileKey = cryptocore.CombineKeyfileDigests(keyfileDigests)
+	}
+	masterKey := cryptocore.DeriveMasterKeyWithKeyfile(passphrase, h.KDFParams(), keyfileKey, h.KeyMode)
+	bar := progressbar.DefaultBytes(int64(h.PlaintextLen), "decrypting "+label)
+
+	// As on the encrypt side, Reed-Solomon and the cascade need the whole
+	// payload in memory; plain chunked AES-GCM streams straight through.
+	if h.ReedSolomon || h.CipherMode == cryptocore.CipherModeParanoidCascade {
+		payload, err := io.ReadAll(in)
+		if err != nil {
+			return err
+		}
+
+		if h.ReedSolomon {
+			var repaired int
+			payload, repaired, err = rsDecode(payload, int(h.RSDataBlockSize), int(h.RSParityBlockSize), expectedPayloadLen(h))
+			if err != nil {
+				return err
+			}
+			if repaired > 0 && opts.Fix {
+				log.Printf("%s: repaired %d Reed-Solomon shard(s)", label, repaired)
+			}
+		}
+
+		var plaintext []byte
+		switch h.CipherMode {
+		case cryptocore.CipherModeParanoidCascade:
+			if uint64(len(payload)) < cryptocore.CascadeTagSize {
+				return ErrTruncatedFile
+			}
+			var authOK bool
+			plaintext, authOK, err = cryptocore.CascadeDecrypt(masterKey, h.Nonce, headerBytes, payload)
+			if err != nil {
+				return err
+			}
+			if !authOK {
+				if !opts.KeepCorrupted {
+					return cryptocore.ErrAuthenticationFailed
+				}
+				log.Printf("%s: authentication failed; writing best-effort plaintext because --keep-corrupted is set", label)
+			}
+		default:
+			gcm, err := cryptocore.NewGCM(masterKey)
+			if err != nil {
+				return err
+			}
+			var buf bytes.Buffer
+			if err := decryptChunked(bytes.NewReader(payload), &buf, gcm, h.Nonce, headerBytes, h.PlaintextLen, bar); err != nil {
+				if opts.KeepCorrupted {
+					log.Printf("%s: authentication failed; --keep-corrupted has no effect in AES-GCM mode", label)
+				}
+				return err
+			}
+			plaintext = buf.Bytes()
+		}
+		_ = bar.Set64(int64(h.PlaintextLen))
+		_, err = out.Write(plaintext)
+		return err
+	}
+
+	gcm, err := cryptocore.NewGCM(masterKey)
+	if err != nil {
+		return err
+	}
+	return decryptChunked(in, out, gcm, h.Nonce, headerBytes, h.PlaintextLen, bar)
+}
@@ -0,0 +1,108 @@
+package contentenc
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/Napolitain/safedotenv/internal/cryptocore"
+)
+
+func encryptToBuffer(t *testing.T, plaintext []byte, passphrase []byte, keyfileDigests [][]byte, opts Options) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	if err := Encrypt(bytes.NewReader(plaintext), &out, uint64(len(plaintext)), passphrase, keyfileDigests, opts, "test"); err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	return out.Bytes()
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	plaintext := []byte("FOO=bar\nBAZ=qux\n")
+	passphrase := []byte("a passphrase")
+
+	for name, opts := range map[string]Options{
+		"plain AES-GCM":    DefaultOptions(),
+		"Reed-Solomon":     withReedSolomon(DefaultOptions()),
+		"paranoid cascade": withParanoid(DefaultOptions()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			encrypted := encryptToBuffer(t, plaintext, passphrase, nil, opts)
+
+			var out bytes.Buffer
+			err := Decrypt(bytes.NewReader(encrypted), &out, passphrase, nil, DecryptOptions{}, "test")
+			if err != nil {
+				t.Fatalf("Decrypt: %v", err)
+			}
+			if !bytes.Equal(out.Bytes(), plaintext) {
+				t.Errorf("Decrypt returned %q, want %q", out.Bytes(), plaintext)
+			}
+		})
+	}
+}
+
+func TestEncryptDecryptRoundTripWithKeyfiles(t *testing.T) {
+	plaintext := []byte("FOO=bar\n")
+	passphrase := []byte("a passphrase")
+	keyfileDigests := [][]byte{
+		cryptocore.HashKeyfile([]byte("keyfile one")),
+		cryptocore.HashKeyfile([]byte("keyfile two")),
+	}
+
+	encrypted := encryptToBuffer(t, plaintext, passphrase, keyfileDigests, DefaultOptions())
+
+	var out bytes.Buffer
+	if err := Decrypt(bytes.NewReader(encrypted), &out, passphrase, keyfileDigests, DecryptOptions{}, "test"); err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), plaintext) {
+		t.Errorf("Decrypt returned %q, want %q", out.Bytes(), plaintext)
+	}
+}
+
+func TestDecryptWrongPassphrase(t *testing.T) {
+	plaintext := []byte("FOO=bar\n")
+	encrypted := encryptToBuffer(t, plaintext, []byte("right passphrase"), nil, DefaultOptions())
+
+	var out bytes.Buffer
+	err := Decrypt(bytes.NewReader(encrypted), &out, []byte("wrong passphrase"), nil, DecryptOptions{}, "test")
+	if !errors.Is(err, cryptocore.ErrAuthenticationFailed) {
+		t.Errorf("Decrypt error = %v, want %v", err, cryptocore.ErrAuthenticationFailed)
+	}
+}
+
+func TestDecryptWrongKeyfileCount(t *testing.T) {
+	plaintext := []byte("FOO=bar\n")
+	passphrase := []byte("a passphrase")
+	keyfileDigests := [][]byte{cryptocore.HashKeyfile([]byte("the one keyfile"))}
+	encrypted := encryptToBuffer(t, plaintext, passphrase, keyfileDigests, DefaultOptions())
+
+	var out bytes.Buffer
+	err := Decrypt(bytes.NewReader(encrypted), &out, passphrase, nil, DecryptOptions{}, "test")
+	if !errors.Is(err, ErrKeyfileMismatch) {
+		t.Errorf("Decrypt error = %v, want %v", err, ErrKeyfileMismatch)
+	}
+}
+
+func TestDecryptTruncatedFile(t *testing.T) {
+	plaintext := []byte("FOO=bar\nBAZ=qux\n")
+	passphrase := []byte("a passphrase")
+	encrypted := encryptToBuffer(t, plaintext, passphrase, nil, DefaultOptions())
+
+	truncated := encrypted[:len(encrypted)-4]
+	var out bytes.Buffer
+	err := Decrypt(bytes.NewReader(truncated), &out, passphrase, nil, DecryptOptions{}, "test")
+	if err == nil {
+		t.Fatal("Decrypt of a truncated file should fail")
+	}
+}
+
+func withReedSolomon(opts Options) Options {
+	opts.ReedSolomon = true
+	return opts
+}
+
+func withParanoid(opts Options) Options {
+	opts.CipherMode = cryptocore.CipherModeParanoidCascade
+	return opts
+}
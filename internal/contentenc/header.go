@@ -0,0 +1,232 @@
+// Package contentenc implements the on-disk .env-encrypted file format:
+// header parsing/serialization, chunked AES-GCM streaming, the paranoid
+// cascade, and optional Reed-Solomon parity. It builds on the primitives in
+// internal/cryptocore and exposes Encrypt/Decrypt as a reusable library API.
+package contentenc
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/Napolitain/safedotenv/internal/cryptocore"
+)
+
+// File format v5: 2-byte version || 1-byte KDF id || 1-byte cipher mode ||
+// 4-byte PBKDF2 iteration count || 4-byte Argon2id time || 4-byte Argon2id
+// memory (KiB) || 1-byte Argon2id parallelism || 16-byte salt || 12-byte
+// nonce || 1-byte Reed-Solomon flag || 2-byte RS data block size || 2-byte
+// RS parity block size || 8-byte plaintext length || 1-byte key mode ||
+// 1-byte keyfile count || payload. Only the fields relevant to the recorded
+// KDF/cipher mode/RS flag/key mode are meaningful, but all are always
+// present so the header has a fixed size.
+//
+// For CipherModeAESGCM the payload is a sequence of independently sealed
+// chunkPlaintextSize-byte chunks (the last one shorter): each chunk's nonce
+// is the file nonce's first 8 bytes concatenated with a 4-byte big-endian
+// chunk counter, and its AAD is header||counter||isLast. The chunk count
+// follows from the plaintext length in the (authenticated) header, so a file
+// cut short is reported as ErrTruncatedFile before GCM sees a partial chunk.
+// For CipherModeParanoidCascade the whole plaintext is still sealed in one
+// piece: Serpent-CTR(AES-CTR(plaintext)) followed by a 64-byte HMAC-SHA512
+// tag over header+ciphertext. When Reed-Solomon is enabled, the payload on
+// disk is the RS-encoded form of whichever of the above produced it; the
+// pre-encoding length is derived from the plaintext length field rather than
+// stored separately (see expectedPayloadLen). Key mode and keyfile count let
+// Decrypt tell immediately whether it's missing a keyfile, rather than
+// reporting a generic authentication failure (see ErrKeyfileMismatch).
+const (
+	FormatVersion = 5
+
+	// rsShardUnit is the byte size of a single Reed-Solomon shard. RS block
+	// size and parity size (stored in the header) must each be a multiple of
+	// it, so e.g. the default 128/8 split is 16 data shards + 1 parity shard
+	// of rsShardUnit bytes apiece.
+	rsShardUnit              = 8
+	rsDataBlockSizeDefault   = 128
+	rsParityBlockSizeDefault = 8
+
+	// chunkPlaintextSize is how much plaintext goes into a single AES-GCM
+	// seal in CipherModeAESGCM, so Encrypt/Decrypt never have to hold a
+	// whole large .env file in memory at once.
+	chunkPlaintextSize = 64 * 1024
+)
+
+const HeaderSize = 2 + 1 + 1 + 4 + 4 + 4 + 1 + cryptocore.SaltSize + cryptocore.NonceSize + 1 + 2 + 2 + 8 + 1 + 1
+
+// ErrTruncatedFile is returned by Decrypt when the input is too short to
+// contain a valid header and ciphertext.
+var ErrTruncatedFile = errors.New("safedotenv: encrypted file is truncated")
+
+// ErrUnsupportedVersion is returned when the file header declares a format
+// version this build does not know how to parse.
+var ErrUnsupportedVersion = errors.New("safedotenv: unsupported file format version")
+
+// ErrUnrepairable is returned when a Reed-Solomon block has more corrupted
+// shards than its parity can reconstruct.
+var ErrUnrepairable = errors.New("safedotenv: Reed-Solomon parity was insufficient to repair corrupted data")
+
+// ErrKeyfileMismatch is returned by Decrypt when the number of keyfiles
+// supplied doesn't match the count recorded in the header, which would
+// otherwise surface as an opaque authentication failure.
+var ErrKeyfileMismatch = errors.New("safedotenv: wrong number of keyfiles supplied for this file")
+
+// Options controls how a new .env-encrypted file is produced. Decrypt needs
+// none of these: they're read back from the file header.
+type Options struct {
+	KDF        cryptocore.KDF
+	CipherMode cryptocore.CipherMode
+
+	Argon2Time        uint32
+	Argon2MemoryKB    uint32
+	Argon2Parallelism uint8
+
+	ReedSolomon bool
+
+	// KeyMode and KeyfileCount are set by Encrypt itself, from whether a
+	// passphrase and/or keyfiles were supplied; callers don't need to set
+	// them.
+	KeyMode      cryptocore.KeyMode
+	KeyfileCount uint8
+}
+
+// DefaultOptions mirrors the historical behaviour: PBKDF2-SHA256 with a
+// single AES-256-GCM layer and no Reed-Solomon parity.
+func DefaultOptions() Options {
+	return Options{
+		KDF:               cryptocore.KDFPBKDF2,
+		CipherMode:        cryptocore.CipherModeAESGCM,
+		Argon2Time:        cryptocore.Argon2TimeDefault,
+		Argon2MemoryKB:    cryptocore.Argon2MemoryKBDefault,
+		Argon2Parallelism: cryptocore.Argon2ParallelismDef,
+	}
+}
+
+// DecryptOptions controls how Decrypt behaves when it encounters
+// Reed-Solomon-repairable or unauthenticated data; it has no bearing on
+// which cryptographic pipeline is used, since that is read from the header.
+type DecryptOptions struct {
+	// Fix reports how many Reed-Solomon shards were repaired, if any.
+	Fix bool
+	// KeepCorrupted writes out the best-effort plaintext even when final
+	// authentication fails, mirroring Picocrypt's -k flag. It has no effect
+	// in CipherModeAESGCM, since the standard library's GCM cannot produce
+	// unauthenticated plaintext.
+	KeepCorrupted bool
+}
+
+// Header is the parsed form of a .env-encrypted file's fixed-size header.
+type Header struct {
+	KDF        cryptocore.KDF
+	CipherMode cryptocore.CipherMode
+
+	PBKDF2Iterations  uint32
+	Argon2Time        uint32
+	Argon2MemoryKB    uint32
+	Argon2Parallelism uint8
+
+	Salt  []byte
+	Nonce []byte
+
+	ReedSolomon       bool
+	RSDataBlockSize   uint16
+	RSParityBlockSize uint16
+	PlaintextLen      uint64
+
+	KeyMode      cryptocore.KeyMode
+	KeyfileCount uint8
+}
+
+// KDFParams extracts the fields cryptocore.DeriveMasterKey needs from h.
+func (h *Header) KDFParams() cryptocore.KDFParams {
+	return cryptocore.KDFParams{
+		KDF:               h.KDF,
+		Salt:              h.Salt,
+		PBKDF2Iterations:  h.PBKDF2Iterations,
+		Argon2Time:        h.Argon2Time,
+		Argon2MemoryKB:    h.Argon2MemoryKB,
+		Argon2Parallelism: h.Argon2Parallelism,
+	}
+}
+
+// BuildHeader serializes a new header for opts/salt/nonce/plaintextLen,
+// returning both the wire bytes (used as AEAD additional data) and the
+// parsed struct.
+func BuildHeader(opts Options, salt, nonce []byte, plaintextLen uint64) ([]byte, *Header) {
+	h := &Header{
+		KDF:               opts.KDF,
+		CipherMode:        opts.CipherMode,
+		PBKDF2Iterations:  cryptocore.PBKDF2Iterations,
+		Argon2Time:        opts.Argon2Time,
+		Argon2MemoryKB:    opts.Argon2MemoryKB,
+		Argon2Parallelism: opts.Argon2Parallelism,
+		Salt:              salt,
+		Nonce:             nonce,
+		ReedSolomon:       opts.ReedSolomon,
+		RSDataBlockSize:   rsDataBlockSizeDefault,
+		RSParityBlockSize: rsParityBlockSizeDefault,
+		PlaintextLen:      plaintextLen,
+		KeyMode:           opts.KeyMode,
+		KeyfileCount:      opts.KeyfileCount,
+	}
+
+	buf := make([]byte, HeaderSize)
+	binary.BigEndian.PutUint16(buf[0:2], FormatVersion)
+	buf[2] = byte(h.KDF)
+	buf[3] = byte(h.CipherMode)
+	binary.BigEndian.PutUint32(buf[4:8], h.PBKDF2Iterations)
+	binary.BigEndian.PutUint32(buf[8:12], h.Argon2Time)
+	binary.BigEndian.PutUint32(buf[12:16], h.Argon2MemoryKB)
+	buf[16] = h.Argon2Parallelism
+	copy(buf[17:17+cryptocore.SaltSize], salt)
+	copy(buf[17+cryptocore.SaltSize:17+cryptocore.SaltSize+cryptocore.NonceSize], nonce)
+	offset := 17 + cryptocore.SaltSize + cryptocore.NonceSize
+	if h.ReedSolomon {
+		buf[offset] = 1
+	}
+	binary.BigEndian.PutUint16(buf[offset+1:offset+3], h.RSDataBlockSize)
+	binary.BigEndian.PutUint16(buf[offset+3:offset+5], h.RSParityBlockSize)
+	binary.BigEndian.PutUint64(buf[offset+5:offset+13], h.PlaintextLen)
+	buf[offset+13] = byte(h.KeyMode)
+	buf[offset+14] = h.KeyfileCount
+	return buf, h
+}
+
+// ParseHeader deserializes a header previously produced by BuildHeader.
+func ParseHeader(data []byte) (*Header, error) {
+	if len(data) < HeaderSize {
+		return nil, ErrTruncatedFile
+	}
+	version := binary.BigEndian.Uint16(data[0:2])
+	if version != FormatVersion {
+		return nil, ErrUnsupportedVersion
+	}
+	saltStart := 17
+	nonceStart := saltStart + cryptocore.SaltSize
+	offset := nonceStart + cryptocore.NonceSize
+	return &Header{
+		KDF:               cryptocore.KDF(data[2]),
+		CipherMode:        cryptocore.CipherMode(data[3]),
+		PBKDF2Iterations:  binary.BigEndian.Uint32(data[4:8]),
+		Argon2Time:        binary.BigEndian.Uint32(data[8:12]),
+		Argon2MemoryKB:    binary.BigEndian.Uint32(data[12:16]),
+		Argon2Parallelism: data[16],
+		Salt:              data[saltStart:nonceStart],
+		Nonce:             data[nonceStart:offset],
+		ReedSolomon:       data[offset] == 1,
+		RSDataBlockSize:   binary.BigEndian.Uint16(data[offset+1 : offset+3]),
+		RSParityBlockSize: binary.BigEndian.Uint16(data[offset+3 : offset+5]),
+		PlaintextLen:      binary.BigEndian.Uint64(data[offset+5 : offset+13]),
+		KeyMode:           cryptocore.KeyMode(data[offset+13]),
+		KeyfileCount:      data[offset+14],
+	}, nil
+}
+
+// expectedPayloadLen derives the size of the payload Decrypt should see
+// before any Reed-Solomon decoding, purely from the plaintext length and
+// cipher mode recorded in the header.
+func expectedPayloadLen(h *Header) uint64 {
+	if h.CipherMode == cryptocore.CipherModeParanoidCascade {
+		return h.PlaintextLen + cryptocore.CascadeTagSize
+	}
+	return h.PlaintextLen + uint64(numChunksFor(int64(h.PlaintextLen)))*cryptocore.GCMOverhead
+}
@@ -0,0 +1,145 @@
+package contentenc
+
+import (
+	"hash/crc32"
+
+	"github.com/klauspost/reedsolomon"
+)
+
+// shardChecksumSize is the size of the CRC32 checksum rsEncode stores
+// alongside every shard. A single parity shard can only erasure-correct
+// shards at *known* locations; it cannot, by itself, tell which shard is
+// corrupted. The checksums are what let rsDecode identify the bad shard(s)
+// before asking the Reed-Solomon decoder to reconstruct them.
+const shardChecksumSize = 4
+
+func shardChecksum(shard []byte) uint32 {
+	return crc32.ChecksumIEEE(shard)
+}
+
+// rsEncode splits payload into dataBlockSize-byte blocks (zero-padding the
+// last one), appends paritySize parity bytes to each using a systematic
+// Reed-Solomon code over rsShardUnit-byte shards, and appends a per-shard
+// CRC32 checksum after every block so rsDecode can locate corruption.
+func rsEncode(payload []byte, dataBlockSize, paritySize int) ([]byte, error) {
+	dataShards := dataBlockSize / rsShardUnit
+	parityShards := paritySize / rsShardUnit
+	enc, err := reedsolomon.New(dataShards, parityShards)
+	if err != nil {
+		return nil, err
+	}
+
+	padded := payload
+	if rem := len(payload) % dataBlockSize; rem != 0 {
+		padded = make([]byte, len(payload)+dataBlockSize-rem)
+		copy(padded, payload)
+	}
+
+	encodedBlockSize := dataBlockSize + paritySize + (dataShards+parityShards)*shardChecksumSize
+	out := make([]byte, 0, len(padded)/dataBlockSize*encodedBlockSize)
+	for blockStart := 0; blockStart < len(padded); blockStart += dataBlockSize {
+		block := padded[blockStart : blockStart+dataBlockSize]
+		shards := make([][]byte, dataShards+parityShards)
+		for i := 0; i < dataShards; i++ {
+			shards[i] = block[i*rsShardUnit : (i+1)*rsShardUnit]
+		}
+		for i := dataShards; i < dataShards+parityShards; i++ {
+			shards[i] = make([]byte, rsShardUnit)
+		}
+		if err := enc.Encode(shards); err != nil {
+			return nil, err
+		}
+		for _, shard := range shards {
+			out = append(out, shard...)
+		}
+		for _, shard := range shards {
+			out = appendUint32(out, shardChecksum(shard))
+		}
+	}
+	return out, nil
+}
+
+// rsDecode reverses rsEncode. For each block it first checks every shard's
+// CRC32 checksum to find which, if any, are corrupted, then reconstructs
+// exactly those (known) locations from parity rather than guessing. It
+// reports how many shards were repaired in total, and returns
+// ErrUnrepairable if a block has more corrupted shards than its parity can
+// reconstruct, or if a reconstructed shard's checksum still doesn't match
+// (meaning the corruption wasn't what the checksums identified).
+func rsDecode(encoded []byte, dataBlockSize, paritySize int, payloadLen uint64) ([]byte, int, error) {
+	dataShards := dataBlockSize / rsShardUnit
+	parityShards := paritySize / rsShardUnit
+	shardCount := dataShards + parityShards
+	blockSize := dataBlockSize + paritySize + shardCount*shardChecksumSize
+	if len(encoded)%blockSize != 0 {
+		return nil, 0, ErrTruncatedFile
+	}
+	enc, err := reedsolomon.New(dataShards, parityShards)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	repaired := 0
+	out := make([]byte, 0, len(encoded)/blockSize*dataBlockSize)
+	for blockStart := 0; blockStart < len(encoded); blockStart += blockSize {
+		block := encoded[blockStart : blockStart+blockSize]
+		shards := make([][]byte, shardCount)
+		for i := range shards {
+			shards[i] = block[i*rsShardUnit : (i+1)*rsShardUnit]
+		}
+		checksums := block[shardCount*rsShardUnit:]
+
+		var badIdx []int
+		for i, shard := range shards {
+			want := readUint32(checksums[i*shardChecksumSize : (i+1)*shardChecksumSize])
+			if shardChecksum(shard) != want {
+				badIdx = append(badIdx, i)
+			}
+		}
+
+		if len(badIdx) > 0 {
+			if len(badIdx) > parityShards {
+				return nil, repaired, ErrUnrepairable
+			}
+			trial := make([][]byte, shardCount)
+			for i, shard := range shards {
+				trial[i] = shard
+			}
+			for _, i := range badIdx {
+				trial[i] = nil
+			}
+			if err := enc.Reconstruct(trial); err != nil {
+				return nil, repaired, ErrUnrepairable
+			}
+			ok, err := enc.Verify(trial)
+			if err != nil || !ok {
+				return nil, repaired, ErrUnrepairable
+			}
+			for _, i := range badIdx {
+				want := readUint32(checksums[i*shardChecksumSize : (i+1)*shardChecksumSize])
+				if shardChecksum(trial[i]) != want {
+					return nil, repaired, ErrUnrepairable
+				}
+			}
+			shards = trial
+			repaired += len(badIdx)
+		}
+
+		for i := 0; i < dataShards; i++ {
+			out = append(out, shards[i]...)
+		}
+	}
+
+	if uint64(len(out)) < payloadLen {
+		return nil, repaired, ErrTruncatedFile
+	}
+	return out[:payloadLen], repaired, nil
+}
+
+func appendUint32(out []byte, v uint32) []byte {
+	return append(out, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func readUint32(b []byte) uint32 {
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
@@ -0,0 +1,238 @@
+// Package cryptocore implements the low-level cryptographic primitives used
+// by safedotenv: KDF selection, master-key derivation, AES-GCM construction,
+// and the Serpent+AES "paranoid" cascade. It knows nothing about file
+// formats or chunking — that lives in internal/contentenc.
+package cryptocore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"io"
+
+	"github.com/aead/serpent"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// KDF identifies the key derivation function used to turn a passphrase into
+// key material. It is recorded in the file header so Decrypt can
+// reconstruct the exact pipeline without the caller having to know how a
+// file was encrypted.
+type KDF byte
+
+const (
+	KDFPBKDF2 KDF = iota
+	KDFArgon2id
+)
+
+// CipherMode selects the symmetric construction used for the bulk of the
+// file. CipherModeParanoidCascade layers Serpent-256 over AES-256 for users
+// who want defense-in-depth against a future break in either cipher.
+type CipherMode byte
+
+const (
+	CipherModeAESGCM CipherMode = iota
+	CipherModeParanoidCascade
+)
+
+// KeyMode records how a file's master key was produced, so Decrypt can fail
+// fast with a clear message (e.g. "this file needs a keyfile") instead of
+// just reporting an authentication failure.
+type KeyMode byte
+
+const (
+	KeyModePassphraseOnly KeyMode = iota
+	KeyModeKeyfileOnly
+	KeyModeCombined
+)
+
+const (
+	SaltSize              = 16
+	NonceSize             = 12
+	PBKDF2Iterations      = 600_000
+	Argon2TimeDefault     = 3
+	Argon2MemoryKBDefault = 64 * 1024
+	Argon2ParallelismDef  = 4
+	CascadeTagSize        = sha512.Size
+
+	// GCMOverhead is the fixed size of the tag AES-GCM appends to its
+	// output, independent of plaintext length.
+	GCMOverhead = 16
+
+	// KeyfileDigestSize is the size of a single keyfile's BLAKE2b-512 digest,
+	// and so also of the XOR-combined digest across multiple keyfiles.
+	KeyfileDigestSize = blake2b.Size
+)
+
+// ErrAuthenticationFailed is returned when a GCM tag or cascade MAC does not
+// verify, meaning the ciphertext or header was tampered with (or the
+// passphrase/keyfiles are wrong).
+var ErrAuthenticationFailed = errors.New("safedotenv: authentication failed, file may have been tampered with or passphrase is wrong")
+
+// KDFParams bundles the KDF choice and every tunable its variants need, so
+// DeriveMasterKey never has to guess which fields are meaningful.
+type KDFParams struct {
+	KDF KDF
+
+	Salt []byte
+
+	PBKDF2Iterations uint32
+
+	Argon2Time        uint32
+	Argon2MemoryKB    uint32
+	Argon2Parallelism uint8
+}
+
+// DeriveMasterKey runs the selected KDF over passphrase, producing the
+// 32-byte master key that either seeds AES-GCM directly or is expanded into
+// the cascade subkeys via HKDF.
+func DeriveMasterKey(passphrase []byte, p KDFParams) []byte {
+	switch p.KDF {
+	case KDFArgon2id:
+		return argon2.IDKey(passphrase, p.Salt, p.Argon2Time, p.Argon2MemoryKB, p.Argon2Parallelism, 32)
+	default:
+		return pbkdf2.Key(passphrase, p.Salt, int(p.PBKDF2Iterations), 32, sha256.New)
+	}
+}
+
+// HashKeyfile reduces a keyfile's raw contents to a fixed-size BLAKE2b-512
+// digest, so keyfiles of any length or format can be combined and used as
+// key material uniformly.
+func HashKeyfile(data []byte) []byte {
+	sum := blake2b.Sum512(data)
+	return sum[:]
+}
+
+// CombineKeyfileDigests XORs a set of keyfile digests (as produced by
+// HashKeyfile) together into a single KeyfileDigestSize-byte value. XOR
+// means the combined key only depends on having every keyfile present, not
+// on their order, and is cheap to compute incrementally as keyfiles are
+// read.
+func CombineKeyfileDigests(digests [][]byte) []byte {
+	combined := make([]byte, KeyfileDigestSize)
+	for _, d := range digests {
+		for i := range combined {
+			combined[i] ^= d[i]
+		}
+	}
+	return combined
+}
+
+// DeriveMasterKeyWithKeyfile produces the 32-byte master key for mode,
+// mixing the passphrase-derived KDF output with keyfileKey (the output of
+// CombineKeyfileDigests) as appropriate:
+//
+//   - KeyModePassphraseOnly: identical to DeriveMasterKey.
+//   - KeyModeKeyfileOnly: the keyfile digest is the key; the KDF never runs.
+//   - KeyModeCombined: the passphrase-derived key is mixed with the keyfile
+//     digest via HKDF-Extract, so neither alone determines the master key.
+func DeriveMasterKeyWithKeyfile(passphrase []byte, p KDFParams, keyfileKey []byte, mode KeyMode) []byte {
+	switch mode {
+	case KeyModeKeyfileOnly:
+		return keyfileKey[:32]
+	case KeyModeCombined:
+		base := DeriveMasterKey(passphrase, p)
+		extracted := hkdf.Extract(sha256.New, base, keyfileKey)
+		return extracted[:32]
+	default:
+		return DeriveMasterKey(passphrase, p)
+	}
+}
+
+// NewGCM builds an AES-256-GCM AEAD from a 32-byte master key.
+func NewGCM(masterKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// CascadeSubkeys expands the master key into independent AES key/IV,
+// Serpent key/IV and HMAC key via HKDF-SHA256, so a weakness in one derived
+// value can't be leveraged against another.
+func CascadeSubkeys(masterKey, nonce []byte) (aesKey, aesIV, serpentKey, serpentIV, macKey []byte) {
+	expand := func(info string, length int) []byte {
+		out := make([]byte, length)
+		reader := hkdf.New(sha256.New, masterKey, nonce, []byte(info))
+		if _, err := io.ReadFull(reader, out); err != nil {
+			panic(err) // hkdf.Read only fails if length exceeds its output limit
+		}
+		return out
+	}
+	aesKey = expand("safedotenv-cascade-aes-key", 32)
+	aesIV = expand("safedotenv-cascade-aes-iv", aes.BlockSize)
+	serpentKey = expand("safedotenv-cascade-serpent-key", 32)
+	serpentIV = expand("safedotenv-cascade-serpent-iv", serpent.BlockSize)
+	macKey = expand("safedotenv-cascade-mac-key", 32)
+	return
+}
+
+// CascadeEncrypt implements the --paranoid pipeline: encrypt-then-encrypt
+// with independent AES-256-CTR and Serpent-256-CTR layers, authenticated as
+// a whole with HMAC-SHA512 over aad+ciphertext.
+func CascadeEncrypt(masterKey, nonce, aad, plaintext []byte) ([]byte, error) {
+	aesKey, aesIV, serpentKey, serpentIV, macKey := CascadeSubkeys(masterKey, nonce)
+
+	aesBlock, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, err
+	}
+	afterAES := make([]byte, len(plaintext))
+	cipher.NewCTR(aesBlock, aesIV).XORKeyStream(afterAES, plaintext)
+
+	serpentBlock, err := serpent.NewCipher(serpentKey)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := make([]byte, len(afterAES))
+	cipher.NewCTR(serpentBlock, serpentIV).XORKeyStream(ciphertext, afterAES)
+
+	mac := hmac.New(sha512.New, macKey)
+	mac.Write(aad)
+	mac.Write(ciphertext)
+	tag := mac.Sum(nil)
+
+	return append(ciphertext, tag...), nil
+}
+
+// CascadeDecrypt verifies the HMAC tag, then peels off the Serpent and AES
+// layers in reverse order regardless of the verification result: the caller
+// decides whether to keep unauthenticated output. sealed must be at least
+// CascadeTagSize bytes; the caller is responsible for that check since only
+// it knows how to report a truncated file.
+func CascadeDecrypt(masterKey, nonce, aad, sealed []byte) (plaintext []byte, authOK bool, err error) {
+	ciphertext := sealed[:len(sealed)-CascadeTagSize]
+	tag := sealed[len(sealed)-CascadeTagSize:]
+
+	aesKey, aesIV, serpentKey, serpentIV, macKey := CascadeSubkeys(masterKey, nonce)
+
+	mac := hmac.New(sha512.New, macKey)
+	mac.Write(aad)
+	mac.Write(ciphertext)
+	expectedTag := mac.Sum(nil)
+	authOK = subtle.ConstantTimeCompare(tag, expectedTag) == 1
+
+	serpentBlock, err := serpent.NewCipher(serpentKey)
+	if err != nil {
+		return nil, false, err
+	}
+	afterSerpent := make([]byte, len(ciphertext))
+	cipher.NewCTR(serpentBlock, serpentIV).XORKeyStream(afterSerpent, ciphertext)
+
+	aesBlock, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, false, err
+	}
+	plaintext = make([]byte, len(afterSerpent))
+	cipher.NewCTR(aesBlock, aesIV).XORKeyStream(plaintext, afterSerpent)
+
+	return plaintext, authOK, nil
+}
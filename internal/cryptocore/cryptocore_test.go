@@ -0,0 +1,107 @@
+package cryptocore
+
+import (
+	"bytes"
+	"testing"
+)
+
+func testKDFParams() KDFParams {
+	// PBKDF2 with a low iteration count so the test suite stays fast; the
+	// production default (PBKDF2Iterations) is exercised indirectly via
+	// internal/contentenc's header round-trip tests.
+	return KDFParams{
+		KDF:              KDFPBKDF2,
+		Salt:             bytes.Repeat([]byte{0x42}, SaltSize),
+		PBKDF2Iterations: 10,
+	}
+}
+
+func TestDeriveMasterKeyWithKeyfileModes(t *testing.T) {
+	params := testKDFParams()
+	passphrase := []byte("correct horse battery staple")
+	keyfileKey := HashKeyfile([]byte("keyfile contents"))
+
+	passphraseOnly := DeriveMasterKeyWithKeyfile(passphrase, params, nil, KeyModePassphraseOnly)
+	if !bytes.Equal(passphraseOnly, DeriveMasterKey(passphrase, params)) {
+		t.Error("KeyModePassphraseOnly should match DeriveMasterKey")
+	}
+
+	keyfileOnly := DeriveMasterKeyWithKeyfile(nil, params, keyfileKey, KeyModeKeyfileOnly)
+	if !bytes.Equal(keyfileOnly, keyfileKey[:32]) {
+		t.Error("KeyModeKeyfileOnly should be the keyfile digest truncated to 32 bytes")
+	}
+
+	combined := DeriveMasterKeyWithKeyfile(passphrase, params, keyfileKey, KeyModeCombined)
+	if bytes.Equal(combined, passphraseOnly) || bytes.Equal(combined, keyfileOnly) {
+		t.Error("KeyModeCombined should differ from both passphrase-only and keyfile-only keys")
+	}
+
+	// Changing either input should change the combined key.
+	otherKeyfileKey := HashKeyfile([]byte("a different keyfile"))
+	if bytes.Equal(combined, DeriveMasterKeyWithKeyfile(passphrase, params, otherKeyfileKey, KeyModeCombined)) {
+		t.Error("KeyModeCombined should depend on the keyfile key")
+	}
+	if bytes.Equal(combined, DeriveMasterKeyWithKeyfile([]byte("wrong passphrase"), params, keyfileKey, KeyModeCombined)) {
+		t.Error("KeyModeCombined should depend on the passphrase")
+	}
+}
+
+func TestCombineKeyfileDigests(t *testing.T) {
+	a := HashKeyfile([]byte("file a"))
+	b := HashKeyfile([]byte("file b"))
+
+	combined := CombineKeyfileDigests([][]byte{a, b})
+	reordered := CombineKeyfileDigests([][]byte{b, a})
+	if !bytes.Equal(combined, reordered) {
+		t.Error("CombineKeyfileDigests should not depend on order (XOR is commutative)")
+	}
+
+	single := CombineKeyfileDigests([][]byte{a})
+	if !bytes.Equal(single, a) {
+		t.Error("CombineKeyfileDigests of a single digest should be that digest")
+	}
+}
+
+func TestCascadeRoundTrip(t *testing.T) {
+	masterKey := DeriveMasterKey([]byte("passphrase"), testKDFParams())
+	nonce := bytes.Repeat([]byte{0x01}, NonceSize)
+	aad := []byte("header bytes used as AAD")
+	plaintext := []byte("the quick brown fox jumps over the lazy dog")
+
+	sealed, err := CascadeEncrypt(masterKey, nonce, aad, plaintext)
+	if err != nil {
+		t.Fatalf("CascadeEncrypt: %v", err)
+	}
+
+	recovered, authOK, err := CascadeDecrypt(masterKey, nonce, aad, sealed)
+	if err != nil {
+		t.Fatalf("CascadeDecrypt: %v", err)
+	}
+	if !authOK {
+		t.Error("CascadeDecrypt should authenticate an untampered payload")
+	}
+	if !bytes.Equal(recovered, plaintext) {
+		t.Errorf("CascadeDecrypt returned %q, want %q", recovered, plaintext)
+	}
+}
+
+func TestCascadeDecryptDetectsTamper(t *testing.T) {
+	masterKey := DeriveMasterKey([]byte("passphrase"), testKDFParams())
+	nonce := bytes.Repeat([]byte{0x01}, NonceSize)
+	aad := []byte("header bytes used as AAD")
+	plaintext := []byte("the quick brown fox jumps over the lazy dog")
+
+	sealed, err := CascadeEncrypt(masterKey, nonce, aad, plaintext)
+	if err != nil {
+		t.Fatalf("CascadeEncrypt: %v", err)
+	}
+	sealed[0] ^= 0xFF
+
+	_, authOK, err := CascadeDecrypt(masterKey, nonce, aad, sealed)
+	if err != nil {
+		t.Fatalf("CascadeDecrypt: %v", err)
+	}
+	if authOK {
+		t.Error("CascadeDecrypt should not authenticate a tampered payload")
+	}
+}